@@ -0,0 +1,36 @@
+package openai
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	// requestsTotal counts OpenAI API calls by provider and outcome
+	// (success, failure, retry, circuit_open, budget_exhausted).
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Subsystem: "openai",
+		Name:      "requests_total",
+		Help:      "Total number of OpenAI API requests made, by provider and outcome.",
+	}, []string{"provider", "outcome"})
+
+	// tokensTotal counts tokens consumed by provider and kind (prompt,
+	// completion).
+	tokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Subsystem: "openai",
+		Name:      "tokens_total",
+		Help:      "Total number of OpenAI tokens consumed, by provider and token kind.",
+	}, []string{"provider", "kind"})
+
+	// costUsdTotal accumulates the estimated USD cost of OpenAI usage by
+	// provider, based on configured per-model pricing.
+	costUsdTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Subsystem: "openai",
+		Name:      "cost_usd_total",
+		Help:      "Total estimated USD cost of OpenAI API usage, by provider.",
+	}, []string{"provider"})
+)
+
+func init() {
+	prometheus.MustRegister(requestsTotal, tokensTotal, costUsdTotal)
+}
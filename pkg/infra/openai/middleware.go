@@ -0,0 +1,234 @@
+// Package openai provides a middleware that enforces the operational
+// limits configured under [openai] (pkg/setting.OpenAiLimits) around calls
+// to an OpenAI-compatible API: rate limiting, retry with backoff, a
+// circuit breaker, and a monthly cost budget.
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// ErrBudgetExhausted is returned once the configured monthly USD budget has
+// been spent; no further calls are made until the next billing window.
+var ErrBudgetExhausted = errors.New("openai: monthly budget exhausted")
+
+// ErrCircuitOpen is returned while the circuit breaker is open after too
+// many consecutive failures.
+var ErrCircuitOpen = errors.New("openai: circuit breaker is open")
+
+const circuitCooldown = 30 * time.Second
+
+// Usage describes the token usage of a completed call, used for cost
+// accounting and metrics.
+type Usage struct {
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Call performs one OpenAI API invocation.
+type Call func(ctx context.Context) (Usage, error)
+
+// Middleware wraps calls to a single configured provider with rate
+// limiting, retries, a circuit breaker and cost accounting.
+type Middleware struct {
+	provider string
+	limits   *setting.OpenAiLimits
+
+	requests *tokenBucket
+	tokens   *tokenBucket
+
+	mu              sync.Mutex
+	consecutiveFail int
+	openUntil       time.Time
+	billingMonth    time.Month
+	billingYear     int
+	spentUsd        float64
+}
+
+// NewMiddleware builds a Middleware for provider using limits. limits may
+// be nil, in which case Do enforces nothing and just invokes the call.
+func NewMiddleware(provider string, limits *setting.OpenAiLimits) *Middleware {
+	m := &Middleware{provider: provider, limits: limits}
+
+	if limits != nil {
+		m.requests = newTokenBucket(limits.RequestsPerMinute)
+		m.tokens = newTokenBucket(limits.TokensPerMinute)
+	}
+
+	year, month, _ := time.Now().Date()
+	m.billingYear, m.billingMonth = year, month
+
+	return m
+}
+
+// Do runs call under the configured limits, retrying on failure up to
+// MaxRetries times with exponential backoff and jitter. estimatedTokens is
+// used to reserve capacity against the tokens-per-minute limit before the
+// call is made.
+func (m *Middleware) Do(ctx context.Context, estimatedTokens int, call Call) error {
+	if m.limits == nil {
+		_, err := call(ctx)
+		return err
+	}
+
+	if err := m.checkBudget(); err != nil {
+		requestsTotal.WithLabelValues(m.provider, "budget_exhausted").Inc()
+		return err
+	}
+
+	if err := m.checkCircuit(); err != nil {
+		requestsTotal.WithLabelValues(m.provider, "circuit_open").Inc()
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= m.limits.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleepWithContext(ctx, m.backoff(attempt)); err != nil {
+				return err
+			}
+			requestsTotal.WithLabelValues(m.provider, "retry").Inc()
+		}
+
+		// Every real upstream call, including retries, has to clear the
+		// rate limiters -- a retry storm is exactly the traffic
+		// requests_per_minute/tokens_per_minute exist to cap.
+		if err := m.requests.take(ctx, 1); err != nil {
+			return err
+		}
+		if err := m.tokens.take(ctx, estimatedTokens); err != nil {
+			return err
+		}
+
+		usage, err := call(ctx)
+		if err == nil {
+			m.recordSuccess(usage)
+			return nil
+		}
+		lastErr = err
+	}
+
+	m.recordFailure()
+	return fmt.Errorf("openai call to %q failed after %d attempts: %w", m.provider, m.limits.MaxRetries+1, lastErr)
+}
+
+// maxBackoffShift caps the exponent used by backoff's 2^(attempt-1)
+// computation so it can never overflow a time.Duration (int64 nanoseconds),
+// regardless of how many retries are configured.
+const maxBackoffShift = 20
+
+// maxBackoff is the ceiling applied to any computed backoff delay.
+const maxBackoff = 5 * time.Minute
+
+func (m *Middleware) backoff(attempt int) time.Duration {
+	base := m.limits.RetryBackoff
+	if base <= 0 {
+		base = time.Second
+	}
+
+	shift := attempt - 1
+	if shift > maxBackoffShift {
+		shift = maxBackoffShift
+	}
+
+	exp := base * time.Duration(math.Pow(2, float64(shift)))
+	if exp <= 0 || exp > maxBackoff {
+		exp = maxBackoff
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(exp) + 1))
+
+	return exp/2 + jitter/2
+}
+
+func (m *Middleware) checkCircuit() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if time.Now().Before(m.openUntil) {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+func (m *Middleware) recordSuccess(usage Usage) {
+	m.mu.Lock()
+	m.consecutiveFail = 0
+	m.openUntil = time.Time{}
+	m.mu.Unlock()
+
+	requestsTotal.WithLabelValues(m.provider, "success").Inc()
+	tokensTotal.WithLabelValues(m.provider, "prompt").Add(float64(usage.PromptTokens))
+	tokensTotal.WithLabelValues(m.provider, "completion").Add(float64(usage.CompletionTokens))
+
+	m.recordCost(usage)
+}
+
+func (m *Middleware) recordFailure() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	requestsTotal.WithLabelValues(m.provider, "failure").Inc()
+
+	m.consecutiveFail++
+	if m.limits.CircuitBreakerThreshold > 0 && m.consecutiveFail >= m.limits.CircuitBreakerThreshold {
+		m.openUntil = time.Now().Add(circuitCooldown)
+	}
+}
+
+func (m *Middleware) recordCost(usage Usage) {
+	cost, ok := m.limits.ModelCosts[usage.Model]
+	if !ok {
+		return
+	}
+
+	usd := (float64(usage.PromptTokens)/1000)*cost.PromptCostPer1k +
+		(float64(usage.CompletionTokens)/1000)*cost.CompletionCostPer1k
+	if usd <= 0 {
+		return
+	}
+
+	costUsdTotal.WithLabelValues(m.provider).Add(usd)
+
+	m.mu.Lock()
+	m.spentUsd += usd
+	m.mu.Unlock()
+}
+
+func (m *Middleware) checkBudget() error {
+	if m.limits.BudgetUsd <= 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	year, month, _ := time.Now().Date()
+	if year != m.billingYear || month != m.billingMonth {
+		m.billingYear, m.billingMonth = year, month
+		m.spentUsd = 0
+	}
+
+	if m.spentUsd >= m.limits.BudgetUsd {
+		return ErrBudgetExhausted
+	}
+	return nil
+}
+
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
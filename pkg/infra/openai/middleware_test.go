@@ -0,0 +1,70 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware_NoLimitsPassesThrough(t *testing.T) {
+	m := NewMiddleware("default", nil)
+
+	called := false
+	err := m.Do(context.Background(), 10, func(ctx context.Context) (Usage, error) {
+		called = true
+		return Usage{}, nil
+	})
+
+	require.NoError(t, err)
+	require.True(t, called)
+}
+
+func TestMiddleware_BackoffNeverOverflowsWithHighMaxRetries(t *testing.T) {
+	m := NewMiddleware("default", &setting.OpenAiLimits{MaxRetries: 40})
+
+	for attempt := 1; attempt <= 60; attempt++ {
+		d := m.backoff(attempt)
+		require.Greater(t, d.Nanoseconds(), int64(0), "backoff must stay positive at attempt %d", attempt)
+		require.LessOrEqual(t, d, maxBackoff, "backoff must be clamped at attempt %d", attempt)
+	}
+}
+
+func TestMiddleware_CircuitBreakerOpensAfterThreshold(t *testing.T) {
+	m := NewMiddleware("default", &setting.OpenAiLimits{
+		MaxRetries:              0,
+		CircuitBreakerThreshold: 2,
+	})
+
+	failingCall := func(ctx context.Context) (Usage, error) {
+		return Usage{}, errors.New("boom")
+	}
+
+	require.Error(t, m.Do(context.Background(), 1, failingCall))
+	require.Error(t, m.Do(context.Background(), 1, failingCall))
+
+	err := m.Do(context.Background(), 1, failingCall)
+	require.ErrorIs(t, err, ErrCircuitOpen)
+}
+
+func TestMiddleware_BudgetExhausted(t *testing.T) {
+	m := NewMiddleware("default", &setting.OpenAiLimits{
+		MaxRetries: 0,
+		BudgetUsd:  0.01,
+		ModelCosts: map[string]setting.OpenAiModelCost{
+			"gpt-4": {PromptCostPer1k: 100},
+		},
+	})
+
+	err := m.Do(context.Background(), 1, func(ctx context.Context) (Usage, error) {
+		return Usage{Model: "gpt-4", PromptTokens: 1000}, nil
+	})
+	require.NoError(t, err)
+
+	err = m.Do(context.Background(), 1, func(ctx context.Context) (Usage, error) {
+		return Usage{Model: "gpt-4", PromptTokens: 1000}, nil
+	})
+	require.ErrorIs(t, err, ErrBudgetExhausted)
+}
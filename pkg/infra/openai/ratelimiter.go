@@ -0,0 +1,67 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// tokenBucket is a simple per-minute rate limiter: it holds up to max units
+// and refills continuously at max units per minute. A zero max disables
+// limiting entirely.
+type tokenBucket struct {
+	mu       sync.Mutex
+	max      float64
+	tokens   float64
+	lastFill time.Time
+}
+
+func newTokenBucket(perMinute int) *tokenBucket {
+	return &tokenBucket{
+		max:      float64(perMinute),
+		tokens:   float64(perMinute),
+		lastFill: time.Now(),
+	}
+}
+
+// take blocks until n units are available, or ctx is done. n can never be
+// satisfied if it exceeds the bucket's capacity, so that case is rejected
+// immediately instead of busy-polling forever.
+func (b *tokenBucket) take(ctx context.Context, n int) error {
+	if b.max <= 0 {
+		return nil
+	}
+
+	if float64(n) > b.max {
+		return fmt.Errorf("openai: requested %d units but the rate limit only allows %.0f per minute", n, b.max)
+	}
+
+	for {
+		b.mu.Lock()
+		b.refill()
+		if b.tokens >= float64(n) {
+			b.tokens -= float64(n)
+			b.mu.Unlock()
+			return nil
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (b *tokenBucket) refill() {
+	now := time.Now()
+	elapsed := now.Sub(b.lastFill)
+	b.lastFill = now
+
+	b.tokens += elapsed.Minutes() * b.max
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
@@ -0,0 +1,51 @@
+package openai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucket_Unlimited(t *testing.T) {
+	b := newTokenBucket(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, b.take(ctx, 1_000_000))
+}
+
+func TestTokenBucket_DrainsThenBlocks(t *testing.T) {
+	b := newTokenBucket(1)
+
+	require.NoError(t, b.take(context.Background(), 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := b.take(ctx, 1)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestTokenBucket_RejectsRequestLargerThanCapacity(t *testing.T) {
+	b := newTokenBucket(10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	err := b.take(ctx, 1000)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, context.DeadlineExceeded, "should fail fast, not spin until the context times out")
+}
+
+func TestTokenBucket_Refills(t *testing.T) {
+	b := newTokenBucket(60) // 1 token/sec
+	require.NoError(t, b.take(context.Background(), 60))
+
+	b.lastFill = b.lastFill.Add(-time.Second) // simulate a second elapsing
+	b.refill()
+
+	require.GreaterOrEqual(t, b.tokens, 1.0)
+}
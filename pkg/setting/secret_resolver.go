@@ -0,0 +1,202 @@
+package setting
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secretResolverCache caches resolved file- and vault-backed secrets for the
+// lifetime of the process so repeated reads of the same setting don't hit
+// disk or a vault backend on every call.
+var (
+	secretResolverCacheMu sync.Mutex
+	secretResolverCache   = map[string]string{}
+)
+
+// SecretVaultReader reads a field from a secret stored at path in an
+// external vault (e.g. HashiCorp Vault). Code that wires in a concrete
+// vault client should call SetSecretVaultReader during startup, before
+// settings referencing $__vault{} are read.
+type SecretVaultReader interface {
+	ReadSecret(path, field string) (string, error)
+}
+
+var secretVaultReader SecretVaultReader
+
+// SetSecretVaultReader registers the vault client used to resolve
+// $__vault{path#field} secret references.
+func SetSecretVaultReader(r SecretVaultReader) {
+	secretVaultReader = r
+}
+
+// resolveSecret interprets secret references of the form:
+//
+//	$__env{NAME}          - read from the NAME environment variable
+//	$__file{path}         - read the contents of the file at path, trimmed
+//	$__vault{path#field}  - read field from the vault secret at path
+//
+// A value that doesn't match any of these forms is returned unchanged, so
+// plaintext values in config files keep working.
+func resolveSecret(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, "$__env{") && strings.HasSuffix(raw, "}"):
+		name := strings.TrimSuffix(strings.TrimPrefix(raw, "$__env{"), "}")
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %q referenced by $__env{} is not set", name)
+		}
+		return val, nil
+	case strings.HasPrefix(raw, "$__file{") && strings.HasSuffix(raw, "}"):
+		path := strings.TrimSuffix(strings.TrimPrefix(raw, "$__file{"), "}")
+		return resolveSecretFile(path)
+	case strings.HasPrefix(raw, "$__vault{") && strings.HasSuffix(raw, "}"):
+		ref := strings.TrimSuffix(strings.TrimPrefix(raw, "$__vault{"), "}")
+		return resolveSecretVault(ref)
+	default:
+		return raw, nil
+	}
+}
+
+// isSecretFileRef reports whether raw is a $__file{path} reference, and if
+// so returns the path.
+func isSecretFileRef(raw string) (path string, ok bool) {
+	if strings.HasPrefix(raw, "$__file{") && strings.HasSuffix(raw, "}") {
+		return strings.TrimSuffix(strings.TrimPrefix(raw, "$__file{"), "}"), true
+	}
+	return "", false
+}
+
+func resolveSecretFile(path string) (string, error) {
+	secretResolverCacheMu.Lock()
+	defer secretResolverCacheMu.Unlock()
+
+	if val, ok := secretResolverCache[path]; ok {
+		return val, nil
+	}
+
+	val, err := readSecretFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	secretResolverCache[path] = val
+	return val, nil
+}
+
+func readSecretFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file %q: %w", path, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func resolveSecretVault(ref string) (string, error) {
+	parts := strings.SplitN(ref, "#", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid $__vault{} reference %q: expected format \"path#field\"", ref)
+	}
+
+	cacheKey := "vault:" + ref
+	secretResolverCacheMu.Lock()
+	defer secretResolverCacheMu.Unlock()
+
+	if val, ok := secretResolverCache[cacheKey]; ok {
+		return val, nil
+	}
+
+	if secretVaultReader == nil {
+		return "", fmt.Errorf("cannot resolve %q: no vault secret reader configured, call setting.SetSecretVaultReader on startup", ref)
+	}
+
+	val, err := secretVaultReader.ReadSecret(parts[0], parts[1])
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault secret %q: %w", ref, err)
+	}
+
+	secretResolverCache[cacheKey] = val
+	return val, nil
+}
+
+// OpenAiSecretValue is a goroutine-safe container for a secret that can be
+// rotated at runtime by a SecretFileWatcher (see WatchSecretFile). Settings
+// that hold a plain string would race against the watcher goroutine; this
+// type serializes reads and writes behind a mutex and is safe to share
+// across any number of readers.
+type OpenAiSecretValue struct {
+	mu  sync.RWMutex
+	val string
+}
+
+// Get returns the current value. Safe to call from any goroutine, and safe
+// to call on a nil *OpenAiSecretValue (returns "").
+func (s *OpenAiSecretValue) Get() string {
+	if s == nil {
+		return ""
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.val
+}
+
+// set updates the current value.
+func (s *OpenAiSecretValue) set(val string) {
+	s.mu.Lock()
+	s.val = val
+	s.mu.Unlock()
+}
+
+// SecretFileWatcher polls a $__file{}-backed secret for changes and invokes
+// onChange with the new value whenever the file contents change. This is
+// useful for secrets mounted from rotated Kubernetes secrets.
+type SecretFileWatcher struct {
+	stop chan struct{}
+}
+
+// WatchSecretFile starts polling path at the given interval and calls
+// onChange whenever the file's contents differ from the last known value.
+// Call Stop on the returned watcher to stop polling.
+func WatchSecretFile(path string, interval time.Duration, onChange func(newVal string)) (*SecretFileWatcher, error) {
+	last, err := readSecretFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &SecretFileWatcher{stop: make(chan struct{})}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				val, err := readSecretFile(path)
+				if err != nil {
+					continue
+				}
+				if val != last {
+					last = val
+
+					secretResolverCacheMu.Lock()
+					secretResolverCache[path] = val
+					secretResolverCacheMu.Unlock()
+
+					onChange(val)
+				}
+			}
+		}
+	}()
+
+	return w, nil
+}
+
+// Stop stops the watcher's polling goroutine.
+func (w *SecretFileWatcher) Stop() {
+	close(w.stop)
+}
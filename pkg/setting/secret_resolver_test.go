@@ -0,0 +1,65 @@
+package setting
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSecret_Plaintext(t *testing.T) {
+	val, err := resolveSecret("plain-value")
+	require.NoError(t, err)
+	require.Equal(t, "plain-value", val)
+}
+
+func TestResolveSecret_Env(t *testing.T) {
+	t.Setenv("OPENAI_TEST_KEY", "from-env")
+
+	val, err := resolveSecret("$__env{OPENAI_TEST_KEY}")
+	require.NoError(t, err)
+	require.Equal(t, "from-env", val)
+}
+
+func TestResolveSecret_EnvMissing(t *testing.T) {
+	_, err := resolveSecret("$__env{OPENAI_TEST_KEY_DOES_NOT_EXIST}")
+	require.Error(t, err)
+}
+
+func TestResolveSecret_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(path, []byte("from-file\n"), 0o600))
+
+	val, err := resolveSecret("$__file{" + path + "}")
+	require.NoError(t, err)
+	require.Equal(t, "from-file", val)
+}
+
+func TestResolveSecret_VaultWithoutReaderConfigured(t *testing.T) {
+	_, err := resolveSecret("$__vault{secret/openai#api_key}")
+	require.Error(t, err)
+}
+
+func TestResolveSecret_VaultInvalidRef(t *testing.T) {
+	SetSecretVaultReader(fakeVaultReader{})
+	t.Cleanup(func() { SetSecretVaultReader(nil) })
+
+	_, err := resolveSecret("$__vault{no-field-separator}")
+	require.Error(t, err)
+}
+
+func TestResolveSecret_Vault(t *testing.T) {
+	SetSecretVaultReader(fakeVaultReader{"secret/openai": {"api_key": "from-vault"}})
+	t.Cleanup(func() { SetSecretVaultReader(nil) })
+
+	val, err := resolveSecret("$__vault{secret/openai#api_key}")
+	require.NoError(t, err)
+	require.Equal(t, "from-vault", val)
+}
+
+type fakeVaultReader map[string]map[string]string
+
+func (f fakeVaultReader) ReadSecret(path, field string) (string, error) {
+	return f[path][field], nil
+}
@@ -1,9 +1,266 @@
 package setting
 
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// openAiApiKeyWatchInterval is how often a $__file{}-backed api_key is
+// checked for rotation when api_key_watch is enabled.
+const openAiApiKeyWatchInterval = 30 * time.Second
+
+// OpenAiAzureSettings holds the configuration needed to route OpenAI-shaped
+// requests to an Azure OpenAI deployment instead of the public OpenAI API.
+type OpenAiAzureSettings struct {
+	Endpoint   string
+	ApiVersion string
+	// DeploymentMapping maps an OpenAI model name (e.g. "gpt-4") to the
+	// Azure deployment name it should be sent to.
+	DeploymentMapping map[string]string
+}
+
+// OpenAiProviderSettings is one entry of an [openai.provider "name"] block,
+// describing a single upstream the AI subsystem can route requests to.
+type OpenAiProviderSettings struct {
+	// Type is one of "openai", "azure", "localai" or "anthropic-shim".
+	Type     string
+	Endpoint string
+	ApiKey   *OpenAiSecretValue
+	// ApiVersion and DeploymentMapping are only populated when Type is
+	// "azure"; see OpenAiAzureSettings.
+	ApiVersion        string
+	DeploymentMapping map[string]string
+}
+
+// OpenAiRoutingSettings controls which configured provider handles each
+// AI feature.
+type OpenAiRoutingSettings struct {
+	ChatProvider       string
+	EmbeddingsProvider string
+}
+
+const openAiFeatureToggle = "aiAssistant"
+
 func (cfg *Cfg) readOpenAiSettings() error {
 	openaiSection := cfg.Raw.Section("openai")
 
-	cfg.OpenAiApiKey = openaiSection.Key("api_key").MustString("yolo_key_1")
+	// The aiAssistant gate must be the very first thing this function does:
+	// every other [openai] key (including the api_key requirement) is only
+	// meaningful once the subsystem is enabled, and this whole subsystem is
+	// supposed to be optional when it's off.
+	if !cfg.IsFeatureToggleEnabled(openAiFeatureToggle) {
+		return nil
+	}
+
+	cfg.OpenAiApiKey = &OpenAiSecretValue{}
+
+	if rawApiKey := openaiSection.Key("api_key").MustString(""); rawApiKey != "" {
+		apiKey, err := resolveSecret(rawApiKey)
+		if err != nil {
+			return fmt.Errorf("failed to resolve openai.api_key: %w", err)
+		}
+		cfg.OpenAiApiKey.set(apiKey)
+
+		if path, ok := isSecretFileRef(rawApiKey); ok && openaiSection.Key("api_key_watch").MustBool(false) {
+			watcher, err := WatchSecretFile(path, openAiApiKeyWatchInterval, func(newVal string) {
+				cfg.OpenAiApiKey.set(newVal)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to watch openai.api_key file: %w", err)
+			}
+			cfg.OpenAiApiKeyWatcher = watcher
+		}
+	}
+
+	cfg.OpenAiApiBase = openaiSection.Key("api_base").MustString("https://api.openai.com/v1")
+	cfg.OpenAiOrg = openaiSection.Key("organization").MustString("")
+	cfg.OpenAiDefaultModel = openaiSection.Key("default_model").MustString("gpt-4-turbo-preview")
+	cfg.OpenAiDefaultMaxTokens = openaiSection.Key("default_max_tokens").MustInt(2048)
+	cfg.OpenAiRequestTimeout = openaiSection.Key("request_timeout").MustDuration(30 * time.Second)
+
+	azureSection := cfg.Raw.Section("openai.azure")
+	if endpoint := azureSection.Key("endpoint").MustString(""); endpoint != "" {
+		deploymentMapping, err := parseOpenAiDeploymentMapping(azureSection.Key("deployment_mapping").MustString(""))
+		if err != nil {
+			return fmt.Errorf("openai.azure has invalid deployment_mapping: %w", err)
+		}
+
+		cfg.OpenAiAzure = &OpenAiAzureSettings{
+			Endpoint:          endpoint,
+			ApiVersion:        azureSection.Key("api_version").MustString("2023-05-15"),
+			DeploymentMapping: deploymentMapping,
+		}
+	}
+
+	if err := cfg.readOpenAiProviders(openaiSection); err != nil {
+		return err
+	}
+
+	if err := cfg.validateOpenAiRouting(); err != nil {
+		return err
+	}
+
+	cfg.readOpenAiLimits(openaiSection)
+
+	return nil
+}
+
+// readOpenAiProviders collects the legacy single-provider [openai] block
+// (if configured) and any [openai.provider "name"] sections into
+// cfg.OpenAiProviders, then reads the feature routing keys.
+func (cfg *Cfg) readOpenAiProviders(openaiSection *ini.Section) error {
+	cfg.OpenAiProviders = map[string]OpenAiProviderSettings{}
+
+	if cfg.OpenAiApiKey.Get() != "" {
+		// ApiKey shares cfg.OpenAiApiKey's pointer, not a copy, so a
+		// rotated $__file{} secret (see WatchSecretFile) stays in sync
+		// for callers that route through cfg.OpenAiProviders["default"].
+		provider := OpenAiProviderSettings{
+			Type:     "openai",
+			Endpoint: cfg.OpenAiApiBase,
+			ApiKey:   cfg.OpenAiApiKey,
+		}
+
+		if cfg.OpenAiAzure != nil {
+			provider.Type = "azure"
+			provider.Endpoint = cfg.OpenAiAzure.Endpoint
+			provider.ApiVersion = cfg.OpenAiAzure.ApiVersion
+			provider.DeploymentMapping = cfg.OpenAiAzure.DeploymentMapping
+		}
+
+		cfg.OpenAiProviders["default"] = provider
+	}
+
+	for _, section := range cfg.Raw.Sections() {
+		providerName, ok := parseOpenAiSubsectionName("openai.provider", section.Name())
+		if !ok {
+			continue
+		}
+
+		rawKey := section.Key("key").MustString("")
+		if rawKey == "" {
+			return fmt.Errorf("openai provider %q is missing required key %q", providerName, "key")
+		}
+		apiKey, err := resolveSecret(rawKey)
+		if err != nil {
+			return fmt.Errorf("failed to resolve key for openai provider %q: %w", providerName, err)
+		}
+
+		providerKey := &OpenAiSecretValue{}
+		providerKey.set(apiKey)
+
+		providerType := section.Key("type").MustString("openai")
+		if !validOpenAiProviderTypes[providerType] {
+			return fmt.Errorf("openai provider %q has unknown type %q: must be one of openai, azure, localai, anthropic-shim", providerName, providerType)
+		}
+
+		endpoint := section.Key("endpoint").MustString("")
+		if endpoint == "" {
+			return fmt.Errorf("openai provider %q is missing required key %q", providerName, "endpoint")
+		}
+
+		provider := OpenAiProviderSettings{
+			Type:     providerType,
+			Endpoint: endpoint,
+			ApiKey:   providerKey,
+		}
+
+		if providerType == "azure" {
+			provider.ApiVersion = section.Key("api_version").MustString("2023-05-15")
+			deploymentMapping, err := parseOpenAiDeploymentMapping(section.Key("deployment_mapping").MustString(""))
+			if err != nil {
+				return fmt.Errorf("openai provider %q has invalid deployment_mapping: %w", providerName, err)
+			}
+			provider.DeploymentMapping = deploymentMapping
+		}
+
+		cfg.OpenAiProviders[providerName] = provider
+	}
+
+	if len(cfg.OpenAiProviders) == 0 {
+		return fmt.Errorf("feature toggle %q is enabled but no openai provider is configured: set openai.api_key or add an [openai.provider \"name\"] section", openAiFeatureToggle)
+	}
+
+	cfg.OpenAiRouting = &OpenAiRoutingSettings{
+		ChatProvider:       openaiSection.Key("chat_provider").MustString(""),
+		EmbeddingsProvider: openaiSection.Key("embeddings_provider").MustString(""),
+	}
 
 	return nil
 }
+
+// parseOpenAiSubsectionName extracts the instance name from a section named
+// either `<prefix> "name"` or `<prefix>.name`, e.g. parseOpenAiSubsectionName
+// ("openai.provider", `openai.provider "azure-prod"`) returns "azure-prod".
+func parseOpenAiSubsectionName(prefix, name string) (string, bool) {
+	if !strings.HasPrefix(name, prefix) {
+		return "", false
+	}
+
+	// Require a delimiter right after prefix so "openai.provider" doesn't
+	// also match an unrelated section like "openai.providerazure-prod" or
+	// "openai.providers".
+	tail := name[len(prefix):]
+	if tail != "" && tail[0] != '"' && tail[0] != '.' && tail[0] != ' ' {
+		return "", false
+	}
+
+	rest := strings.TrimSpace(tail)
+	rest = strings.Trim(rest, `."`)
+	if rest == "" {
+		return "", false
+	}
+
+	return rest, true
+}
+
+// validOpenAiProviderTypes is the closed set of "type" values accepted by
+// an [openai.provider "name"] section.
+var validOpenAiProviderTypes = map[string]bool{
+	"openai":         true,
+	"azure":          true,
+	"localai":        true,
+	"anthropic-shim": true,
+}
+
+// validateOpenAiRouting checks that every provider referenced by
+// cfg.OpenAiRouting is actually defined in cfg.OpenAiProviders.
+func (cfg *Cfg) validateOpenAiRouting() error {
+	check := func(feature, provider string) error {
+		if provider == "" {
+			return nil
+		}
+		if _, ok := cfg.OpenAiProviders[provider]; !ok {
+			return fmt.Errorf("openai routing for %s references unknown provider %q", feature, provider)
+		}
+		return nil
+	}
+
+	if err := check("chat", cfg.OpenAiRouting.ChatProvider); err != nil {
+		return err
+	}
+	return check("embeddings", cfg.OpenAiRouting.EmbeddingsProvider)
+}
+
+// parseOpenAiDeploymentMapping parses a comma-separated list of
+// model=deployment pairs, e.g. "gpt-4=gpt4-prod,gpt-3.5-turbo=gpt35-prod".
+// It returns an error if any entry isn't a valid "model=deployment" pair,
+// rather than silently dropping it.
+func parseOpenAiDeploymentMapping(raw string) (map[string]string, error) {
+	mapping := map[string]string{}
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return nil, fmt.Errorf("invalid deployment_mapping entry %q: expected \"model=deployment\"", pair)
+		}
+		mapping[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return mapping, nil
+}
@@ -0,0 +1,71 @@
+package setting
+
+import (
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// OpenAiModelCost is the per-1k-token price of a model, used to estimate
+// the USD cost of OpenAI API usage against OpenAiLimits.BudgetUsd.
+type OpenAiModelCost struct {
+	PromptCostPer1k     float64
+	CompletionCostPer1k float64
+}
+
+// maxOpenAiMaxRetries caps openai.max_retries, bounding how many attempts a
+// single call can make. The middleware's backoff is exponential in the retry
+// attempt, but it clamps each individual delay to five minutes by itself.
+const maxOpenAiMaxRetries = 20
+
+// OpenAiLimits are the operational safety limits enforced by
+// pkg/infra/openai around calls to a configured provider.
+type OpenAiLimits struct {
+	RequestsPerMinute       int
+	TokensPerMinute         int
+	MaxRetries              int
+	RetryBackoff            time.Duration
+	CircuitBreakerThreshold int
+	// BudgetUsd is the monthly spend cap across all models; once reached,
+	// calls are refused until the next billing window. Zero means no cap.
+	BudgetUsd float64
+	// ModelCosts is keyed by model name, populated from
+	// [openai.cost "model"] sections.
+	ModelCosts map[string]OpenAiModelCost
+}
+
+// readOpenAiLimits parses the operational safety settings under [openai]
+// and any [openai.cost "model"] sections into cfg.OpenAiLimits.
+func (cfg *Cfg) readOpenAiLimits(openaiSection *ini.Section) {
+	maxRetries := openaiSection.Key("max_retries").MustInt(3)
+	if maxRetries > maxOpenAiMaxRetries {
+		maxRetries = maxOpenAiMaxRetries
+	}
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	limits := &OpenAiLimits{
+		RequestsPerMinute:       openaiSection.Key("requests_per_minute").MustInt(0),
+		TokensPerMinute:         openaiSection.Key("tokens_per_minute").MustInt(0),
+		MaxRetries:              maxRetries,
+		RetryBackoff:            openaiSection.Key("retry_backoff").MustDuration(time.Second),
+		CircuitBreakerThreshold: openaiSection.Key("circuit_breaker_threshold").MustInt(5),
+		BudgetUsd:               openaiSection.Key("budget_usd").MustFloat64(0),
+		ModelCosts:              map[string]OpenAiModelCost{},
+	}
+
+	for _, section := range cfg.Raw.Sections() {
+		model, ok := parseOpenAiSubsectionName("openai.cost", section.Name())
+		if !ok {
+			continue
+		}
+
+		limits.ModelCosts[model] = OpenAiModelCost{
+			PromptCostPer1k:     section.Key("cost_per_1k_prompt_tokens").MustFloat64(0),
+			CompletionCostPer1k: section.Key("cost_per_1k_completion_tokens").MustFloat64(0),
+		}
+	}
+
+	cfg.OpenAiLimits = limits
+}
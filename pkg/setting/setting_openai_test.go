@@ -0,0 +1,190 @@
+package setting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/ini.v1"
+)
+
+func TestReadOpenAiSettings_DisabledToggleSkipsEverything(t *testing.T) {
+	cfg := NewCfg()
+	cfg.FeatureToggles = map[string]bool{}
+	cfg.Raw = ini.Empty()
+
+	// No openai.api_key and no [openai.provider] sections: with the
+	// aiAssistant toggle off, none of that should matter.
+	err := cfg.readOpenAiSettings()
+	require.NoError(t, err)
+	require.Empty(t, cfg.OpenAiProviders)
+}
+
+func TestReadOpenAiSettings_EnabledToggleRequiresAProvider(t *testing.T) {
+	cfg := NewCfg()
+	cfg.FeatureToggles = map[string]bool{openAiFeatureToggle: true}
+	cfg.Raw = ini.Empty()
+
+	err := cfg.readOpenAiSettings()
+	require.Error(t, err)
+}
+
+func TestReadOpenAiSettings_AzureSynthesizesDefaultProvider(t *testing.T) {
+	cfg := NewCfg()
+	cfg.FeatureToggles = map[string]bool{openAiFeatureToggle: true}
+
+	raw := ini.Empty()
+	openaiSection, err := raw.NewSection("openai")
+	require.NoError(t, err)
+	_, err = openaiSection.NewKey("api_key", "secret")
+	require.NoError(t, err)
+
+	azureSection, err := raw.NewSection("openai.azure")
+	require.NoError(t, err)
+	_, err = azureSection.NewKey("endpoint", "https://contoso.openai.azure.com")
+	require.NoError(t, err)
+	_, err = azureSection.NewKey("deployment_mapping", "gpt-4=gpt4-prod")
+	require.NoError(t, err)
+
+	cfg.Raw = raw
+
+	require.NoError(t, cfg.readOpenAiSettings())
+
+	provider, ok := cfg.OpenAiProviders["default"]
+	require.True(t, ok)
+	require.Equal(t, "azure", provider.Type)
+	require.Equal(t, "https://contoso.openai.azure.com", provider.Endpoint)
+	require.Equal(t, "gpt4-prod", provider.DeploymentMapping["gpt-4"])
+	require.Equal(t, "secret", provider.ApiKey.Get())
+}
+
+func TestReadOpenAiSettings_RoutingToUnknownProviderFails(t *testing.T) {
+	cfg := NewCfg()
+	cfg.FeatureToggles = map[string]bool{openAiFeatureToggle: true}
+
+	raw := ini.Empty()
+	openaiSection, err := raw.NewSection("openai")
+	require.NoError(t, err)
+	_, err = openaiSection.NewKey("api_key", "secret")
+	require.NoError(t, err)
+	_, err = openaiSection.NewKey("chat_provider", "azure-prod")
+	require.NoError(t, err)
+
+	cfg.Raw = raw
+
+	err = cfg.readOpenAiSettings()
+	require.Error(t, err)
+}
+
+func TestReadOpenAiSettings_UnknownProviderTypeFails(t *testing.T) {
+	cfg := NewCfg()
+	cfg.FeatureToggles = map[string]bool{openAiFeatureToggle: true}
+
+	raw := ini.Empty()
+	_, err := raw.NewSection("openai")
+	require.NoError(t, err)
+
+	providerSection, err := raw.NewSection(`openai.provider "bogus"`)
+	require.NoError(t, err)
+	_, err = providerSection.NewKey("type", "not-a-real-provider")
+	require.NoError(t, err)
+	_, err = providerSection.NewKey("key", "secret")
+	require.NoError(t, err)
+
+	cfg.Raw = raw
+
+	err = cfg.readOpenAiSettings()
+	require.Error(t, err)
+}
+
+func TestReadOpenAiSettings_ProviderMissingKeyFails(t *testing.T) {
+	cfg := NewCfg()
+	cfg.FeatureToggles = map[string]bool{openAiFeatureToggle: true}
+
+	raw := ini.Empty()
+	_, err := raw.NewSection("openai")
+	require.NoError(t, err)
+
+	providerSection, err := raw.NewSection(`openai.provider "bare"`)
+	require.NoError(t, err)
+	_, err = providerSection.NewKey("type", "openai")
+	require.NoError(t, err)
+	_, err = providerSection.NewKey("endpoint", "https://api.openai.com/v1")
+	require.NoError(t, err)
+
+	cfg.Raw = raw
+
+	err = cfg.readOpenAiSettings()
+	require.Error(t, err)
+}
+
+func TestReadOpenAiSettings_ProviderMissingEndpointFails(t *testing.T) {
+	cfg := NewCfg()
+	cfg.FeatureToggles = map[string]bool{openAiFeatureToggle: true}
+
+	raw := ini.Empty()
+	_, err := raw.NewSection("openai")
+	require.NoError(t, err)
+
+	providerSection, err := raw.NewSection(`openai.provider "bare"`)
+	require.NoError(t, err)
+	_, err = providerSection.NewKey("type", "openai")
+	require.NoError(t, err)
+	_, err = providerSection.NewKey("key", "secret")
+	require.NoError(t, err)
+
+	cfg.Raw = raw
+
+	err = cfg.readOpenAiSettings()
+	require.Error(t, err)
+}
+
+func TestReadOpenAiSettings_MalformedDeploymentMappingFails(t *testing.T) {
+	cfg := NewCfg()
+	cfg.FeatureToggles = map[string]bool{openAiFeatureToggle: true}
+
+	raw := ini.Empty()
+	_, err := raw.NewSection("openai")
+	require.NoError(t, err)
+
+	providerSection, err := raw.NewSection(`openai.provider "azure-prod"`)
+	require.NoError(t, err)
+	_, err = providerSection.NewKey("type", "azure")
+	require.NoError(t, err)
+	_, err = providerSection.NewKey("endpoint", "https://contoso.openai.azure.com")
+	require.NoError(t, err)
+	_, err = providerSection.NewKey("key", "secret")
+	require.NoError(t, err)
+	_, err = providerSection.NewKey("deployment_mapping", "gpt-4:gpt4-prod")
+	require.NoError(t, err)
+
+	cfg.Raw = raw
+
+	err = cfg.readOpenAiSettings()
+	require.Error(t, err)
+}
+
+func TestParseOpenAiSubsectionName(t *testing.T) {
+	cases := []struct {
+		name      string
+		section   string
+		wantName  string
+		wantFound bool
+	}{
+		{name: "quoted", section: `openai.provider "azure-prod"`, wantName: "azure-prod", wantFound: true},
+		{name: "dotted", section: "openai.provider.azure-prod", wantName: "azure-prod", wantFound: true},
+		{name: "unrelated section", section: "smtp", wantFound: false},
+		{name: "prefix with nothing after", section: "openai.provider", wantFound: false},
+		{name: "prefix-colliding unrelated section", section: "openai.providers", wantFound: false},
+		{name: "prefix-colliding typo section", section: "openai.providerazure-prod", wantFound: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, found := parseOpenAiSubsectionName("openai.provider", tc.section)
+			require.Equal(t, tc.wantFound, found)
+			if tc.wantFound {
+				require.Equal(t, tc.wantName, got)
+			}
+		})
+	}
+}